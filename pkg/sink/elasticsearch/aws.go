@@ -0,0 +1,123 @@
+/*
+Copyright 2021 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/pkg/errors"
+)
+
+const defaultAWSService = "es"
+
+// sigv4RoundTripper signs every outgoing request with AWS Signature V4
+// before delegating to the wrapped transport, so requests are accepted by a
+// managed Amazon OpenSearch / Elasticsearch Service domain.
+type sigv4RoundTripper struct {
+	next    http.RoundTripper
+	signer  *v4.Signer
+	creds   aws.CredentialsProvider
+	region  string
+	service string
+}
+
+func newSigV4RoundTripper(ctx context.Context, cfg *AWSConfig, next http.RoundTripper) (*sigv4RoundTripper, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	service := cfg.Service
+	if service == "" {
+		service = defaultAWSService
+	}
+
+	var opts []func(*awsconfig.LoadOptions) error
+	opts = append(opts, awsconfig.WithRegion(cfg.Region))
+	if cfg.Profile != "" {
+		opts = append(opts, awsconfig.WithSharedConfigProfile(cfg.Profile))
+	}
+	if cfg.AccessKeyId != "" && cfg.SecretAccessKey != "" {
+		opts = append(opts, awsconfig.WithCredentialsProvider(
+			aws.NewCredentialsCache(aws.CredentialsProviderFunc(
+				func(ctx context.Context) (aws.Credentials, error) {
+					return aws.Credentials{
+						AccessKeyID:     cfg.AccessKeyId,
+						SecretAccessKey: cfg.SecretAccessKey,
+						SessionToken:    cfg.SessionToken,
+					}, nil
+				}))))
+	}
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "load AWS config failed")
+	}
+
+	creds := awsCfg.Credentials
+	if cfg.RoleArn != "" {
+		stsClient := sts.NewFromConfig(awsCfg)
+		creds = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(stsClient, cfg.RoleArn))
+	}
+
+	return &sigv4RoundTripper{
+		next:    next,
+		signer:  v4.NewSigner(),
+		creds:   creds,
+		region:  cfg.Region,
+		service: service,
+	}, nil
+}
+
+func (rt *sigv4RoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, errors.WithMessage(err, "read request body for signing failed")
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	hash := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(hash[:])
+
+	creds, err := rt.creds.Retrieve(req.Context())
+	if err != nil {
+		return nil, errors.WithMessage(err, "retrieve AWS credentials failed")
+	}
+
+	signCtx, cancel := context.WithTimeout(req.Context(), 10*time.Second)
+	defer cancel()
+	if err := rt.signer.SignHTTP(signCtx, creds, req, payloadHash, rt.service, rt.region, time.Now()); err != nil {
+		return nil, errors.WithMessage(err, "sign request with SigV4 failed")
+	}
+
+	return rt.next.RoundTrip(req)
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2021 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backend
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	es "github.com/olivere/elastic/v7"
+)
+
+// fakeTransport answers the root-path version ping with a fixed version and
+// every other request with the same canned bulk response, which is enough to exercise both backends' request/response translation without a
+// live Elasticsearch or OpenSearch cluster.
+type fakeTransport struct {
+	body string
+}
+
+func (t *fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Path == "/" {
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(bytes.NewBufferString(`{"version":{"number":"7.10.0"}}`)),
+			Header:     make(http.Header),
+		}, nil
+	}
+	return &http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(bytes.NewBufferString(t.body)),
+		Header:     make(http.Header),
+	}, nil
+}
+
+const sampleBulkResponse = `{"errors":true,"items":[{"index":{"status":200}},{"index":{"status":429,"error":{"reason":"too many requests"}}}]}`
+
+func parityAction() BulkAction {
+	return BulkAction{Index: "test", DocumentId: "1", Doc: []byte(`{"message":"hello"}`)}
+}
+
+func TestElasticBackend_Bulk(t *testing.T) {
+	cli, err := es.NewClient(
+		es.SetURL("http://fake-es:9200"),
+		es.SetSniff(false),
+		es.SetHealthcheck(false),
+		es.SetHttpClient(&http.Client{Transport: &fakeTransport{body: sampleBulkResponse}}),
+	)
+	if err != nil {
+		t.Fatalf("new es client: %v", err)
+	}
+	b := NewElasticBackendWithClient(cli)
+
+	ret, err := b.Bulk(context.Background(), []BulkAction{parityAction(), parityAction()})
+	assertParityResult(t, ret, err)
+}
+
+// recordingTransport captures the last request body it served, so a test
+// can assert on the bulk request actually sent over the wire rather than
+// just the parsed response.
+type recordingTransport struct {
+	fakeTransport
+	lastBody string
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		data, _ := io.ReadAll(req.Body)
+		t.lastBody = string(data)
+	}
+	return t.fakeTransport.RoundTrip(req)
+}
+
+func TestElasticBackend_Bulk_CreateOpType(t *testing.T) {
+	transport := &recordingTransport{fakeTransport: fakeTransport{
+		body: `{"errors":false,"items":[{"create":{"status":201}}]}`,
+	}}
+	cli, err := es.NewClient(
+		es.SetURL("http://fake-es:9200"),
+		es.SetSniff(false),
+		es.SetHealthcheck(false),
+		es.SetHttpClient(&http.Client{Transport: transport}),
+	)
+	if err != nil {
+		t.Fatalf("new es client: %v", err)
+	}
+	b := NewElasticBackendWithClient(cli)
+
+	ret, err := b.Bulk(context.Background(), []BulkAction{{Op: "create", Index: "ds-test", Doc: []byte(`{"message":"hello"}`)}})
+	if err != nil {
+		t.Fatalf("bulk with create action failed: %v", err)
+	}
+	if ret.Errors {
+		t.Fatalf("expected no errors, got %+v", ret)
+	}
+	if !strings.Contains(transport.lastBody, `"create"`) {
+		t.Errorf("expected bulk request body to use the create action, got: %s", transport.lastBody)
+	}
+	if strings.Contains(transport.lastBody, `"index":{"_index"`) {
+		t.Errorf("expected bulk request to NOT use an index action, got: %s", transport.lastBody)
+	}
+}
+
+func TestOpenSearchBackend_Bulk(t *testing.T) {
+	b, err := NewOpenSearchBackend([]string{"http://fake-os:9200"}, "", "", &fakeTransport{body: sampleBulkResponse})
+	if err != nil {
+		t.Fatalf("new opensearch backend: %v", err)
+	}
+
+	ret, err := b.Bulk(context.Background(), []BulkAction{parityAction(), parityAction()})
+	assertParityResult(t, ret, err)
+}
+
+func assertParityResult(t *testing.T, ret *BulkResult, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("bulk failed: %v", err)
+	}
+	if !ret.Errors {
+		t.Fatal("expected Errors to be true")
+	}
+	if len(ret.Items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(ret.Items))
+	}
+	if ret.Items[0].Failed() {
+		t.Error("expected first item to succeed")
+	}
+	if !ret.Items[1].Failed() || ret.Items[1].Status != 429 {
+		t.Errorf("expected second item to fail with 429, got %+v", ret.Items[1])
+	}
+}
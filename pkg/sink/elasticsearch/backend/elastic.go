@@ -0,0 +1,138 @@
+/*
+Copyright 2021 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backend
+
+import (
+	"context"
+	"encoding/json"
+
+	es "github.com/olivere/elastic/v7"
+)
+
+// ElasticBackend talks to an Elasticsearch cluster via olivere/elastic/v7.
+type ElasticBackend struct {
+	cli      *es.Client
+	pingHost string
+}
+
+func NewElasticBackend(hosts []string, opts ...es.ClientOptionFunc) (*ElasticBackend, error) {
+	cli, err := es.NewClient(opts...)
+	if err != nil {
+		return nil, err
+	}
+	backend := NewElasticBackendWithClient(cli)
+	if len(hosts) > 0 {
+		backend.pingHost = hosts[0]
+	}
+	return backend, nil
+}
+
+// NewElasticBackendWithClient wraps an already-constructed client, mainly so
+// tests can inject one backed by a fake transport.
+func NewElasticBackendWithClient(cli *es.Client) *ElasticBackend {
+	return &ElasticBackend{cli: cli}
+}
+
+func (b *ElasticBackend) Bulk(ctx context.Context, actions []BulkAction) (*BulkResult, error) {
+	req := b.cli.Bulk()
+	for _, action := range actions {
+		req.Add(toBulkableRequest(action))
+	}
+
+	ret, err := req.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BulkResult{Errors: ret.Errors, Items: make([]BulkItemResult, len(ret.Items))}
+	for i, item := range ret.Items {
+		var bri *es.BulkResponseItem
+		for _, v := range item {
+			bri = v
+			break
+		}
+		if bri == nil {
+			continue
+		}
+		r := BulkItemResult{Status: bri.Status}
+		if bri.Error != nil {
+			r.Error = bri.Error.Reason
+		}
+		result.Items[i] = r
+	}
+	return result, nil
+}
+
+// toBulkableRequest translates a BulkAction into a request. olivere/elastic
+// has no separate "create" request type: `create` vs `index` is just the
+// OpType on a BulkIndexRequest.
+func toBulkableRequest(action BulkAction) es.BulkableRequest {
+	req := es.NewBulkIndexRequest().Index(action.Index).Doc(json.RawMessage(action.Doc))
+	if action.Op == "create" {
+		req.OpType("create")
+	}
+	if action.Etype != "" {
+		req.Type(action.Etype)
+	}
+	if action.DocumentId != "" {
+		req.Id(action.DocumentId)
+	}
+	if action.Routing != "" {
+		req.Routing(action.Routing)
+	}
+	if action.Pipeline != "" {
+		req.Pipeline(action.Pipeline)
+	}
+	if action.VersionType != "" {
+		req.Version(action.Version).VersionType(action.VersionType)
+	}
+	return req
+}
+
+func (b *ElasticBackend) Version(ctx context.Context) (string, error) {
+	info, _, err := b.cli.Ping(b.pingHost).Do(ctx)
+	if err != nil {
+		return "", err
+	}
+	return info.Version.Number, nil
+}
+
+func (b *ElasticBackend) Do(ctx context.Context, method, path string, body []byte) (*RawResponse, error) {
+	var bodyArg interface{}
+	if len(body) > 0 {
+		bodyArg = json.RawMessage(body)
+	}
+
+	res, err := b.cli.PerformRequest(ctx, es.PerformRequestOptions{
+		Method: method,
+		Path:   path,
+		Body:   bodyArg,
+	})
+	if err != nil {
+		if res != nil {
+			return &RawResponse{StatusCode: res.StatusCode, Body: res.Body}, nil
+		}
+		return nil, err
+	}
+	return &RawResponse{StatusCode: res.StatusCode, Body: res.Body}, nil
+}
+
+func (b *ElasticBackend) Stop() {
+	if b.cli != nil {
+		b.cli.Stop()
+	}
+}
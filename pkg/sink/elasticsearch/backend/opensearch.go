@@ -0,0 +1,190 @@
+/*
+Copyright 2021 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	opensearch "github.com/opensearch-project/opensearch-go/v2"
+	"github.com/pkg/errors"
+)
+
+// OpenSearchBackend talks to an OpenSearch cluster via the official
+// opensearch-go client, which (unlike olivere/elastic) has no fluent bulk
+// request builder: the NDJSON body is assembled by hand below.
+type OpenSearchBackend struct {
+	cli       *opensearch.Client
+	addresses []string
+}
+
+func NewOpenSearchBackend(addresses []string, username, password string, transport http.RoundTripper) (*OpenSearchBackend, error) {
+	cli, err := opensearch.NewClient(opensearch.Config{
+		Addresses: addresses,
+		Username:  username,
+		Password:  password,
+		Transport: transport,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &OpenSearchBackend{cli: cli, addresses: addresses}, nil
+}
+
+func (b *OpenSearchBackend) Bulk(ctx context.Context, actions []BulkAction) (*BulkResult, error) {
+	var body bytes.Buffer
+	for _, action := range actions {
+		if err := writeBulkAction(&body, action); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := b.cli.Bulk(bytes.NewReader(body.Bytes()), b.cli.Bulk.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, errors.Errorf("opensearch bulk request failed: %s", res.String())
+	}
+
+	var parsed struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int `json:"status"`
+			Error  *struct {
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&parsed); err != nil {
+		return nil, errors.WithMessage(err, "decode opensearch bulk response failed")
+	}
+
+	result := &BulkResult{Errors: parsed.Errors, Items: make([]BulkItemResult, len(parsed.Items))}
+	for i, item := range parsed.Items {
+		for _, v := range item {
+			r := BulkItemResult{Status: v.Status}
+			if v.Error != nil {
+				r.Error = v.Error.Reason
+			}
+			result.Items[i] = r
+			break
+		}
+	}
+	return result, nil
+}
+
+// writeBulkAction appends the action-metadata line and, for index/create,
+// the source-document line, to an NDJSON bulk request body.
+func writeBulkAction(w io.Writer, action BulkAction) error {
+	op := action.Op
+	if op == "" {
+		op = "index"
+	}
+
+	meta := map[string]interface{}{"_index": action.Index}
+	if action.Etype != "" {
+		meta["_type"] = action.Etype
+	}
+	if action.DocumentId != "" {
+		meta["_id"] = action.DocumentId
+	}
+	if action.Routing != "" {
+		meta["routing"] = action.Routing
+	}
+	if action.Pipeline != "" {
+		meta["pipeline"] = action.Pipeline
+	}
+	if action.VersionType != "" {
+		meta["version"] = action.Version
+		meta["version_type"] = action.VersionType
+	}
+
+	if err := writeJSONLine(w, map[string]interface{}{op: meta}); err != nil {
+		return err
+	}
+	_, err := w.Write(append(action.Doc, '\n'))
+	return err
+}
+
+func writeJSONLine(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+func (b *OpenSearchBackend) Version(ctx context.Context) (string, error) {
+	res, err := b.cli.Info(b.cli.Info.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	var info struct {
+		Version struct {
+			Number string `json:"number"`
+		} `json:"version"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+		return "", err
+	}
+	return info.Version.Number, nil
+}
+
+func (b *OpenSearchBackend) Do(ctx context.Context, method, path string, body []byte) (*RawResponse, error) {
+	base := "http://localhost:9200"
+	if len(b.addresses) > 0 {
+		base = b.addresses[0]
+	}
+
+	var bodyReader io.Reader
+	if len(body) > 0 {
+		bodyReader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, base+path, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := b.cli.Perform(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &RawResponse{StatusCode: res.StatusCode, Body: data}, nil
+}
+
+func (b *OpenSearchBackend) Stop() {
+	// opensearch-go has no persistent connection pool to tear down beyond
+	// the underlying http.Transport, which is not closed here.
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2021 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticsearch
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/pkg/errors"
+)
+
+// bootstrap ensures the index template, ILM/ISM policy and write
+// alias/data stream that IndexMode needs exist, creating them from the
+// user-supplied JSON files when missing. Every step PUTs unconditionally
+// rather than check-then-create, so it stays idempotent when several
+// Loggie instances race to bootstrap the same cluster at startup: a
+// template or ILM policy PUT simply overwrites, and an ISM 409 conflict or
+// an "already exists" response from data-stream/alias creation is treated
+// as success.
+func (c *ClientSet) bootstrap(ctx context.Context) error {
+	mode := c.config.IndexMode
+	if mode == "" || mode == IndexModeIndex {
+		return nil
+	}
+
+	bc := c.config.Bootstrap
+
+	if bc.TemplateFile != "" {
+		if err := c.ensureTemplate(ctx, bc.TemplateName, bc.TemplateFile); err != nil {
+			return errors.WithMessage(err, "bootstrap index template failed")
+		}
+	}
+
+	if bc.PolicyFile != "" {
+		if err := c.ensurePolicy(ctx, bc.PolicyName, bc.PolicyFile); err != nil {
+			return errors.WithMessage(err, "bootstrap ILM/ISM policy failed")
+		}
+	}
+
+	switch mode {
+	case IndexModeDataStream:
+		return c.ensureDataStream(ctx, c.config.Index)
+	case IndexModeRolloverAlias:
+		return c.ensureRolloverAlias(ctx, bc.Alias, c.config.Index)
+	default:
+		return errors.Errorf("unsupported indexMode: %s", mode)
+	}
+}
+
+func (c *ClientSet) ensureTemplate(ctx context.Context, name, file string) error {
+	body, err := os.ReadFile(file)
+	if err != nil {
+		return errors.WithMessagef(err, "read index template file %s failed", file)
+	}
+
+	res, err := c.cli.Do(ctx, "PUT", "/_index_template/"+name, body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode >= 300 {
+		return errors.Errorf("create index template %s failed: %s", name, res.Body)
+	}
+	return nil
+}
+
+func (c *ClientSet) ensurePolicy(ctx context.Context, name, file string) error {
+	body, err := os.ReadFile(file)
+	if err != nil {
+		return errors.WithMessagef(err, "read ILM/ISM policy file %s failed", file)
+	}
+
+	path := "/_ilm/policy/" + name
+	if c.config.Type == TypeOpenSearch {
+		path = "/_plugins/_ism/policies/" + name
+	}
+
+	res, err := c.cli.Do(ctx, "PUT", path, body)
+	if err != nil {
+		return err
+	}
+	// ISM returns 409 Conflict when the policy already exists; retrying the
+	// same bootstrap from a second Loggie instance is expected, not fatal.
+	if res.StatusCode >= 300 && res.StatusCode != 409 {
+		return errors.Errorf("create policy %s failed: %s", name, res.Body)
+	}
+	return nil
+}
+
+func (c *ClientSet) ensureDataStream(ctx context.Context, name string) error {
+	res, err := c.cli.Do(ctx, "PUT", "/_data_stream/"+name, nil)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode >= 300 && !alreadyExists(res.Body) {
+		return errors.Errorf("create data stream %s failed: %s", name, res.Body)
+	}
+	return nil
+}
+
+// ensureRolloverAlias creates the first backing index (<name>-000001) with
+// the write alias, for clusters too old for native data streams.
+func (c *ClientSet) ensureRolloverAlias(ctx context.Context, alias, name string) error {
+	if alias == "" {
+		return errors.New("bootstrap.alias is required when indexMode is rollover-alias")
+	}
+
+	firstIndex := name + "-000001"
+	body := []byte(`{"aliases":{"` + alias + `":{"is_write_index":true}}}`)
+
+	res, err := c.cli.Do(ctx, "PUT", "/"+firstIndex, body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode >= 300 && !alreadyExists(res.Body) {
+		return errors.Errorf("create rollover-alias seed index %s failed: %s", firstIndex, res.Body)
+	}
+
+	log.Info("elasticsearch sink bootstrapped rollover alias %s over %s", alias, firstIndex)
+	return nil
+}
+
+func alreadyExists(body []byte) bool {
+	return strings.Contains(string(body), "resource_already_exists_exception") ||
+		strings.Contains(string(body), "already exists")
+}
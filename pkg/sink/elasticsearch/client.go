@@ -18,12 +18,15 @@ package elasticsearch
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	eventer "github.com/loggie-io/loggie/pkg/core/event"
 	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/sink/elasticsearch/backend"
 	"github.com/loggie-io/loggie/pkg/util/pattern"
+	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/loggie-io/loggie/pkg/core/api"
 	"github.com/loggie-io/loggie/pkg/sink/codec"
@@ -35,11 +38,14 @@ import (
 type ClientSet struct {
 	Version             string
 	config              *Config
-	cli                 *es.Client
+	cli                 backend.Client
 	codec               codec.Codec
 	indexPattern        *pattern.Pattern
 	documentIdPattern   *pattern.Pattern
 	defaultIndexPattern *pattern.Pattern
+	pipelinePattern     *pattern.Pattern
+	routingPattern      *pattern.Pattern
+	versionPattern      *pattern.Pattern
 }
 
 type Client interface {
@@ -48,47 +54,126 @@ type Client interface {
 }
 
 func NewClient(config *Config, cod codec.Codec, indexPattern *pattern.Pattern, documentIdPattern *pattern.Pattern,
-	defaultIndexPattern *pattern.Pattern) (*ClientSet, error) {
+	defaultIndexPattern *pattern.Pattern, pipelinePattern *pattern.Pattern, routingPattern *pattern.Pattern,
+	versionPattern *pattern.Pattern) (*ClientSet, error) {
 	for i, h := range config.Hosts {
 		if !strings.HasPrefix(h, "http") && !strings.HasPrefix(h, "https") {
 			config.Hosts[i] = fmt.Sprintf("http://%s", h)
 		}
 	}
-	var opts []es.ClientOptionFunc
-	opts = append(opts, es.SetURL(config.Hosts...))
-	if config.Sniff != nil {
-		opts = append(opts, es.SetSniff(*config.Sniff))
-	} else {
-		// disable sniff by default
-		opts = append(opts, es.SetSniff(false))
-	}
-	if config.Password != "" && config.UserName != "" {
-		opts = append(opts, es.SetBasicAuth(config.UserName, config.Password))
-	}
-	if config.Schema != "" {
-		opts = append(opts, es.SetScheme(config.Schema))
+
+	transport := http.DefaultTransport
+	if config.TLS != nil {
+		tlsTransport, err := newTLSTransport(config.TLS)
+		if err != nil {
+			return nil, errors.WithMessage(err, "build TLS transport failed")
+		}
+		transport = tlsTransport
 	}
-	if config.Gzip != nil {
-		opts = append(opts, es.SetGzip(*config.Gzip))
+	if config.AWS != nil {
+		signer, err := newSigV4RoundTripper(context.Background(), config.AWS, transport)
+		if err != nil {
+			return nil, errors.WithMessage(err, "build AWS SigV4 transport failed")
+		}
+		transport = signer
 	}
 
-	cli, err := es.NewClient(opts...)
+	cli, err := newBackend(config, transport)
 	if err != nil {
 		return nil, err
 	}
 
-	return &ClientSet{
+	clientSet := &ClientSet{
 		cli:                 cli,
 		config:              config,
 		codec:               cod,
 		indexPattern:        indexPattern,
 		documentIdPattern:   documentIdPattern,
 		defaultIndexPattern: defaultIndexPattern,
-	}, nil
+		pipelinePattern:     pipelinePattern,
+		routingPattern:      routingPattern,
+		versionPattern:      versionPattern,
+	}
+
+	pingTimeout := config.PingTimeout
+	if pingTimeout <= 0 {
+		pingTimeout = 5 * time.Second
+	}
+	pingCtx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	if version, err := cli.Version(pingCtx); err != nil {
+		log.Warn("ping elasticsearch to detect version failed: %v", err)
+	} else {
+		clientSet.Version = version
+	}
+
+	if err := clientSet.bootstrap(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return clientSet, nil
+}
+
+// newBackend builds the wire-protocol backend selected by Config.Type. Both
+// distributions share the hosts, basic auth and transport (optional TLS and
+// AWS SigV4); scheme, gzip, sniffing, healthcheck, retrier and sendGetBodyAs
+// are applied only to the elasticsearch backend.
+func newBackend(config *Config, transport http.RoundTripper) (backend.Client, error) {
+	switch config.Type {
+	case TypeOpenSearch:
+		return backend.NewOpenSearchBackend(config.Hosts, config.UserName, config.Password, transport)
+	case TypeElasticsearch, "":
+		var opts []es.ClientOptionFunc
+		opts = append(opts, es.SetURL(config.Hosts...))
+		if config.Sniff != nil {
+			opts = append(opts, es.SetSniff(*config.Sniff))
+		} else {
+			// disable sniff by default
+			opts = append(opts, es.SetSniff(false))
+		}
+		if config.Password != "" && config.UserName != "" {
+			opts = append(opts, es.SetBasicAuth(config.UserName, config.Password))
+		}
+		if config.Schema != "" {
+			opts = append(opts, es.SetScheme(config.Schema))
+		}
+		if config.Gzip != nil {
+			opts = append(opts, es.SetGzip(*config.Gzip))
+		}
+		if config.SniffInterval > 0 {
+			opts = append(opts, es.SetSnifferInterval(config.SniffInterval))
+		}
+		if config.SnifferTimeoutStartup > 0 {
+			opts = append(opts, es.SetSnifferTimeoutStartup(config.SnifferTimeoutStartup))
+		}
+		if hc := config.Healthcheck; hc.Enabled != nil {
+			opts = append(opts, es.SetHealthcheck(*hc.Enabled))
+		}
+		if config.Healthcheck.Interval > 0 {
+			opts = append(opts, es.SetHealthcheckInterval(config.Healthcheck.Interval))
+		}
+		if config.Healthcheck.Timeout > 0 {
+			opts = append(opts, es.SetHealthcheckTimeoutStartup(config.Healthcheck.Timeout))
+		}
+		if config.SendGetBodyAs != "" {
+			opts = append(opts, es.SetSendGetBodyAs(config.SendGetBodyAs))
+		}
+		if config.Retrier.MaxRetries > 0 {
+			opts = append(opts, es.SetRetrier(newBoundedRetrier(config.Retrier)))
+		}
+		opts = append(opts, es.SetHttpClient(&http.Client{Transport: transport}))
+		return backend.NewElasticBackend(config.Hosts, opts...)
+	default:
+		return nil, errors.Errorf("unsupported elasticsearch sink type: %s", config.Type)
+	}
 }
 
 func (c *ClientSet) BulkIndex(ctx context.Context, batch api.Batch) error {
-	req := c.cli.Bulk()
+	if c.config.IndexMode == IndexModeDataStream && c.config.DocumentId != "" {
+		return errors.New("documentId is not supported when indexMode is datastream; data streams only accept auto-generated ids")
+	}
+
+	var items []*bulkItem
 	for _, event := range batch.Events() {
 		headerObj := runtime.NewObject(event.Header())
 
@@ -120,38 +205,69 @@ func (c *ClientSet) BulkIndex(ctx context.Context, batch api.Batch) error {
 			return errors.WithMessagef(err, "codec encode event: %s error", event.String())
 		}
 
-		bulkIndexRequest := es.NewBulkIndexRequest().Index(idx).Doc(json.RawMessage(data))
+		action := backend.BulkAction{Index: idx, Doc: data}
 		if c.config.Etype != "" {
-			bulkIndexRequest.Type(c.config.Etype)
+			action.Etype = c.config.Etype
 		}
 		if c.config.OpType != "" {
-			bulkIndexRequest.OpType(c.config.OpType)
+			action.Op = c.config.OpType
+		}
+		if c.config.IndexMode == IndexModeDataStream {
+			// data streams only accept `create` bulk actions
+			action.Op = "create"
 		}
 		if c.config.DocumentId != "" {
 			id, err := c.documentIdPattern.WithObject(headerObj).Render()
 			if err != nil {
 				return errors.WithMessagef(err, "format documentId %s failed", c.config.DocumentId)
 			}
-			bulkIndexRequest.Id(id)
+			action.DocumentId = id
 		}
 
-		req.Add(bulkIndexRequest)
-	}
+		pipeline := c.config.Pipeline
+		if c.config.PipelinePattern != "" {
+			rendered, err := c.pipelinePattern.WithObject(headerObj).Render()
+			if err != nil {
+				return errors.WithMessagef(err, "format pipelinePattern %s failed", c.config.PipelinePattern)
+			}
+			if rendered != "" {
+				pipeline = rendered
+			}
+		}
+		action.Pipeline = pipeline
 
-	if req.NumberOfActions() == 0 {
-		return errors.WithMessagef(eventer.ErrorDropEvent, "request to elasticsearch bulk is null")
-	}
+		if c.config.RoutingPattern != "" {
+			routing, err := c.routingPattern.WithObject(headerObj).Render()
+			if err != nil {
+				return errors.WithMessagef(err, "format routingPattern %s failed", c.config.RoutingPattern)
+			}
+			action.Routing = routing
+		}
 
-	ret, err := req.Do(ctx)
-	if err != nil {
-		return err
+		if c.config.VersionPattern != "" {
+			versionStr, err := c.versionPattern.WithObject(headerObj).Render()
+			if err != nil {
+				return errors.WithMessagef(err, "format versionPattern %s failed", c.config.VersionPattern)
+			}
+			version, err := strconv.ParseInt(versionStr, 10, 64)
+			if err != nil {
+				return errors.WithMessagef(err, "versionPattern %s rendered non-numeric version %q", c.config.VersionPattern, versionStr)
+			}
+			action.Version = version
+			action.VersionType = c.config.VersionType
+			if action.VersionType == "" {
+				action.VersionType = "external"
+			}
+		}
+
+		items = append(items, &bulkItem{event: event, action: action})
 	}
-	if ret.Errors {
-		out, _ := json.Marshal(ret)
-		return errors.Errorf("request to elasticsearch response error: %s", out)
+
+	if len(items) == 0 {
+		return errors.WithMessagef(eventer.ErrorDropEvent, "request to elasticsearch bulk is null")
 	}
 
-	return nil
+	return c.doBulkWithRetry(ctx, items)
 }
 
 func (c *ClientSet) Stop() {
@@ -0,0 +1,186 @@
+/*
+Copyright 2021 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticsearch
+
+import "time"
+
+const (
+	TypeElasticsearch = "elasticsearch"
+	TypeOpenSearch    = "opensearch"
+)
+
+type Config struct {
+	// Type selects the wire protocol backend: "elasticsearch" (default, via
+	// olivere/elastic) or "opensearch" (via opensearch-go). Both backends are
+	// driven through the same Config and the same Client interface.
+	Type string `yaml:"type,omitempty" default:"elasticsearch"`
+
+	Hosts      []string `yaml:"hosts,omitempty" validate:"required"`
+	UserName   string   `yaml:"userName,omitempty"`
+	Password   string   `yaml:"password,omitempty"`
+	Schema     string   `yaml:"schema,omitempty"`
+	Sniff      *bool    `yaml:"sniff,omitempty"`
+	Gzip       *bool    `yaml:"gzip,omitempty"`
+	Etype      string   `yaml:"etype,omitempty"`
+	OpType     string   `yaml:"opType,omitempty"`
+	DocumentId string   `yaml:"documentId,omitempty"`
+
+	Index               string                    `yaml:"index,omitempty" validate:"required"`
+	IfRenderIndexFailed IfRenderIndexFailedConfig `yaml:"ifRenderIndexFailed,omitempty"`
+
+	Retry RetryConfig `yaml:"retry,omitempty"`
+
+	AWS *AWSConfig `yaml:"aws,omitempty"`
+
+	// IndexMode selects how the target index is managed: a plain "index"
+	// (default, unmanaged), a "datastream" (ILM/ISM-backed, `create`-only
+	// bulk actions), or a "rollover-alias" (a write alias over a sequence of
+	// ILM/ISM-rolled indices, for clusters too old for native data streams).
+	IndexMode string          `yaml:"indexMode,omitempty" default:"index"`
+	Bootstrap BootstrapConfig `yaml:"bootstrap,omitempty"`
+
+	// Pipeline sets a default ingest pipeline for every document; PipelinePattern
+	// templates one from event headers per event, the same way IndexPattern
+	// already does for the index name, and takes precedence over Pipeline
+	// when it renders to a non-empty value.
+	Pipeline        string `yaml:"pipeline,omitempty"`
+	PipelinePattern string `yaml:"pipelinePattern,omitempty"`
+
+	// RoutingPattern templates a custom shard-routing key from event headers.
+	RoutingPattern string `yaml:"routingPattern,omitempty"`
+
+	// VersionPattern templates an external document version from event
+	// headers, for idempotent replay from queue-based sources; VersionType
+	// is the Elasticsearch version_type ("external" or "external_gte") and
+	// defaults to "external" when VersionPattern is set.
+	VersionPattern string `yaml:"versionPattern,omitempty"`
+	VersionType    string `yaml:"versionType,omitempty"`
+
+	Healthcheck           HealthcheckConfig `yaml:"healthcheck,omitempty"`
+	SniffInterval         time.Duration     `yaml:"sniffInterval,omitempty"`
+	SnifferTimeoutStartup time.Duration     `yaml:"snifferTimeoutStartup,omitempty"`
+	Retrier               RetrierConfig     `yaml:"retrier,omitempty"`
+	SendGetBodyAs         string            `yaml:"sendGetBodyAs,omitempty"`
+	TLS                   *TLSConfig        `yaml:"tls,omitempty"`
+
+	// PingTimeout bounds the startup ping used to populate ClientSet.Version,
+	// so downstream code can adapt bulk payload shape (e.g. `_type` only on
+	// Elasticsearch <7).
+	PingTimeout time.Duration `yaml:"pingTimeout,omitempty" default:"5s"`
+}
+
+// HealthcheckConfig controls the periodic background healthcheck
+// olivere/elastic runs against each node to mark it up/down.
+type HealthcheckConfig struct {
+	Enabled  *bool         `yaml:"enabled,omitempty"`
+	Interval time.Duration `yaml:"interval,omitempty"`
+	Timeout  time.Duration `yaml:"timeout,omitempty"`
+}
+
+// RetrierConfig controls the transport-level retry of individual HTTP
+// requests against the cluster (connection refused, node down during a
+// rolling restart), distinct from Retry's application-level retry of
+// individual bulk items.
+type RetrierConfig struct {
+	MaxRetries      int           `yaml:"maxRetries,omitempty"`
+	InitialInterval time.Duration `yaml:"initialInterval,omitempty" default:"100ms"`
+	MaxInterval     time.Duration `yaml:"maxInterval,omitempty" default:"5s"`
+}
+
+// TLSConfig configures a custom CA bundle and/or client certificate for
+// connecting to a cluster behind TLS.
+type TLSConfig struct {
+	CACertFile         string `yaml:"caCertFile,omitempty"`
+	CertFile           string `yaml:"certFile,omitempty"`
+	KeyFile            string `yaml:"keyFile,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify,omitempty"`
+}
+
+const (
+	IndexModeIndex         = "index"
+	IndexModeDataStream    = "datastream"
+	IndexModeRolloverAlias = "rollover-alias"
+)
+
+// BootstrapConfig describes the index template, ILM/ISM policy and write
+// alias/data stream that must exist before the sink can write to a
+// datastream or rollover-alias index; see bootstrap.go.
+type BootstrapConfig struct {
+	TemplateName string `yaml:"templateName,omitempty"`
+	TemplateFile string `yaml:"templateFile,omitempty"`
+	PolicyName   string `yaml:"policyName,omitempty"`
+	PolicyFile   string `yaml:"policyFile,omitempty"`
+	// Alias is the write alias name, only used when IndexMode is
+	// "rollover-alias"; Config.Index is used as the data stream name when
+	// IndexMode is "datastream".
+	Alias string `yaml:"alias,omitempty"`
+}
+
+// AWSConfig signs outgoing requests with AWS Signature V4 so events can be
+// shipped straight to a managed Amazon OpenSearch / Elasticsearch Service
+// domain, without a signing sidecar in front of it.
+type AWSConfig struct {
+	Region string `yaml:"region,omitempty" validate:"required"`
+
+	// AccessKeyId/SecretAccessKey provide static credentials. When both are
+	// empty, Profile (or the default credential chain: env vars, shared
+	// config, EC2/ECS instance role) is used instead.
+	AccessKeyId     string `yaml:"accessKeyId,omitempty"`
+	SecretAccessKey string `yaml:"secretAccessKey,omitempty"`
+	SessionToken    string `yaml:"sessionToken,omitempty"`
+	Profile         string `yaml:"profile,omitempty"`
+
+	// RoleArn, when set, is assumed via STS before signing requests.
+	RoleArn string `yaml:"roleArn,omitempty"`
+
+	// Service is the SigV4 service name to sign for: "es" for Elasticsearch
+	// Service / OpenSearch Service, "aoss" for OpenSearch Serverless.
+	Service string `yaml:"service,omitempty" default:"es"`
+}
+
+type IfRenderIndexFailedConfig struct {
+	IgnoreError  bool   `yaml:"ignoreError,omitempty"`
+	DefaultIndex string `yaml:"defaultIndex,omitempty"`
+	DropEvent    bool   `yaml:"dropEvent,omitempty"`
+}
+
+// RetryConfig controls the exponential backoff retry of bulk items that fail
+// with a retryable status (429, or any 5xx) and where the still-failing items
+// are sent once retries are exhausted.
+type RetryConfig struct {
+	MaxRetries          int           `yaml:"maxRetries,omitempty" default:"3"`
+	InitialInterval     time.Duration `yaml:"initialInterval,omitempty" default:"500ms"`
+	MaxInterval         time.Duration `yaml:"maxInterval,omitempty" default:"30s"`
+	Multiplier          float64       `yaml:"multiplier,omitempty" default:"2"`
+	RandomizationFactor float64       `yaml:"randomizationFactor,omitempty" default:"0.5"`
+
+	DeadLetter DeadLetterConfig `yaml:"deadLetter,omitempty"`
+}
+
+// DeadLetterConfig describes where events are routed once a bulk item has
+// exhausted its retries. At most one destination should be configured; Index
+// takes precedence over File when both are set.
+type DeadLetterConfig struct {
+	// Index sends the failed event to another Elasticsearch index.
+	Index string `yaml:"index,omitempty"`
+	// File appends the failed event, one JSON line per event, to this path.
+	File string `yaml:"file,omitempty"`
+}
+
+func (d DeadLetterConfig) Enabled() bool {
+	return d.Index != "" || d.File != ""
+}
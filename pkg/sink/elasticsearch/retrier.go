@@ -0,0 +1,57 @@
+/*
+Copyright 2021 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticsearch
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	es "github.com/olivere/elastic/v7"
+)
+
+// boundedRetrier wraps an exponential backoff with a hard cap on the number
+// of transport-level retries, since es.ExponentialBackoff alone only gives up
+// once its interval reaches the maximum and ignores RetrierConfig.MaxRetries.
+type boundedRetrier struct {
+	maxRetries int
+	backoff    es.Backoff
+}
+
+func newBoundedRetrier(cfg RetrierConfig) *boundedRetrier {
+	initial := cfg.InitialInterval
+	if initial <= 0 {
+		initial = 100 * time.Millisecond
+	}
+	max := cfg.MaxInterval
+	if max <= 0 {
+		max = 5 * time.Second
+	}
+	return &boundedRetrier{
+		maxRetries: cfg.MaxRetries,
+		backoff:    es.NewExponentialBackoff(initial, max),
+	}
+}
+
+func (r *boundedRetrier) Retry(ctx context.Context, retry int, req *http.Request, resp *http.Response, err error) (time.Duration, bool, error) {
+	// olivere/elastic numbers retries from 1
+	if retry > r.maxRetries {
+		return 0, false, nil
+	}
+	wait, ok := r.backoff.Next(retry)
+	return wait, ok, nil
+}
@@ -0,0 +1,269 @@
+/*
+Copyright 2021 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/core/log"
+	"github.com/loggie-io/loggie/pkg/sink/elasticsearch/backend"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultMaxRetries          = 3
+	defaultInitialInterval     = 500 * time.Millisecond
+	defaultMaxInterval         = 30 * time.Second
+	defaultMultiplier          = 2.0
+	defaultRandomizationFactor = 0.5
+)
+
+// bulkItem pairs the event that produced a bulk action with the action
+// itself, so a failed item can be either resubmitted or routed to the
+// dead-letter destination.
+type bulkItem struct {
+	event  api.Event
+	action backend.BulkAction
+}
+
+// backoff computes the exponential, jittered retry interval for a given
+// attempt, in the spirit of the backoff used by other Elasticsearch clients.
+type backoff struct {
+	cfg RetryConfig
+}
+
+func newBackoff(cfg RetryConfig) *backoff {
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = defaultMaxRetries
+	}
+	if cfg.InitialInterval <= 0 {
+		cfg.InitialInterval = defaultInitialInterval
+	}
+	if cfg.MaxInterval <= 0 {
+		cfg.MaxInterval = defaultMaxInterval
+	}
+	if cfg.Multiplier <= 0 {
+		cfg.Multiplier = defaultMultiplier
+	}
+	if cfg.RandomizationFactor < 0 {
+		cfg.RandomizationFactor = defaultRandomizationFactor
+	}
+	return &backoff{cfg: cfg}
+}
+
+func (b *backoff) next(attempt int) time.Duration {
+	interval := float64(b.cfg.InitialInterval) * pow(b.cfg.Multiplier, attempt)
+	if max := float64(b.cfg.MaxInterval); interval > max {
+		interval = max
+	}
+	delta := interval * b.cfg.RandomizationFactor
+	jittered := interval - delta + rand.Float64()*2*delta
+	if jittered < 0 {
+		jittered = 0
+	}
+	return time.Duration(jittered)
+}
+
+func pow(base float64, exp int) float64 {
+	result := 1.0
+	for i := 0; i < exp; i++ {
+		result *= base
+	}
+	return result
+}
+
+// doBulkWithRetry submits items as a bulk request, and on a retryable
+// per-item error (429 or 5xx) rebuilds a smaller bulk request containing
+// only those items and retries it with an exponential backoff. Once retries
+// are exhausted, the still-failing items are routed to the dead-letter
+// destination, if one is configured, rather than being dropped silently.
+func (c *ClientSet) doBulkWithRetry(ctx context.Context, items []*bulkItem) error {
+	bo := newBackoff(c.config.Retry)
+
+	pending := items
+	var allFailed []*failedItem
+	var lastRet *backend.BulkResult
+	for attempt := 0; attempt <= bo.cfg.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(bo.next(attempt - 1)):
+			}
+		}
+
+		actions := make([]backend.BulkAction, len(pending))
+		for i, item := range pending {
+			actions[i] = item.action
+		}
+
+		ret, err := c.cli.Bulk(ctx, actions)
+		if err != nil {
+			return err
+		}
+		lastRet = ret
+
+		if !ret.Errors {
+			return c.handleFailed(ctx, allFailed)
+		}
+
+		retryable, failed := splitBulkResult(pending, ret)
+		allFailed = append(allFailed, failed...)
+
+		if len(retryable) == 0 {
+			// nothing left worth retrying; whatever failed (this attempt and
+			// any earlier one) is permanent
+			return c.handleFailed(ctx, allFailed)
+		}
+
+		if attempt == bo.cfg.MaxRetries {
+			// retries exhausted: every permanently-failed item plus the ones
+			// still retryable all end up in the dead-letter destination
+			for i, result := range ret.Items {
+				if i < len(pending) && result.Failed() && isRetryableStatus(result.Status) {
+					allFailed = append(allFailed, &failedItem{bulkItem: pending[i], reason: result.Error})
+				}
+			}
+			return c.handleFailed(ctx, allFailed)
+		}
+
+		log.Info("elasticsearch bulk: retrying %d/%d items after attempt %d", len(retryable), len(pending), attempt+1)
+		pending = retryable
+	}
+
+	out, _ := json.Marshal(lastRet)
+	return errors.Errorf("request to elasticsearch response error: %s", out)
+}
+
+// splitBulkResult walks a bulk response in lock-step with the items that
+// produced it and separates items worth retrying (429/5xx) from items that
+// failed for a reason retrying won't fix.
+func splitBulkResult(items []*bulkItem, ret *backend.BulkResult) (retryable []*bulkItem, failed []*failedItem) {
+	for i, result := range ret.Items {
+		if i >= len(items) {
+			break
+		}
+		if !result.Failed() {
+			continue
+		}
+		item := items[i]
+
+		if isRetryableStatus(result.Status) {
+			retryable = append(retryable, item)
+			continue
+		}
+
+		failed = append(failed, &failedItem{bulkItem: item, reason: result.Error})
+	}
+	return retryable, failed
+}
+
+func isRetryableStatus(status int) bool {
+	return status == 429 || status >= 500
+}
+
+// failedItem is a bulkItem that has exhausted its retries, annotated with
+// the Elasticsearch error reason so the dead-letter sink can surface it.
+type failedItem struct {
+	*bulkItem
+	reason string
+}
+
+// handleFailed routes permanently-failed items to the configured dead-letter
+// destination, attaching the ES error reason to the event header. It takes
+// the caller's ctx rather than fabricating a new one, so a hung or
+// unreachable dead-letter index still honors pipeline shutdown/cancellation.
+func (c *ClientSet) handleFailed(ctx context.Context, failed []*failedItem) error {
+	if len(failed) == 0 {
+		return nil
+	}
+
+	dl := c.config.Retry.DeadLetter
+	if !dl.Enabled() {
+		out, _ := json.Marshal(failed)
+		return errors.Errorf("request to elasticsearch response error after retries exhausted: %s", out)
+	}
+
+	for _, f := range failed {
+		f.event.Header()[deadLetterReasonHeader] = f.reason
+	}
+
+	if dl.Index != "" {
+		return c.sendToDeadLetterIndex(ctx, dl.Index, failed)
+	}
+	return c.appendToDeadLetterFile(dl.File, failed)
+}
+
+const deadLetterReasonHeader = "_deadLetterReason"
+
+func (c *ClientSet) sendToDeadLetterIndex(ctx context.Context, index string, failed []*failedItem) error {
+	actions := make([]backend.BulkAction, len(failed))
+	for i, f := range failed {
+		data, err := c.codec.Encode(f.event)
+		if err != nil {
+			return errors.WithMessagef(err, "codec encode dead-letter event: %s error", f.event.String())
+		}
+		actions[i] = backend.BulkAction{Index: index, Doc: data}
+	}
+
+	ret, err := c.cli.Bulk(ctx, actions)
+	if err != nil {
+		return errors.WithMessage(err, "send events to dead-letter index failed")
+	}
+	if ret.Errors {
+		out, _ := json.Marshal(ret)
+		return errors.Errorf("dead-letter index response error: %s", out)
+	}
+	return nil
+}
+
+// deadLetterLine is the shape of each line written to the dead-letter file:
+// the event header (including the injected ES error reason) alongside the
+// actual encoded document, the same content sendToDeadLetterIndex sends.
+type deadLetterLine struct {
+	Header   map[string]interface{} `json:"header"`
+	Document json.RawMessage        `json:"document"`
+}
+
+func (c *ClientSet) appendToDeadLetterFile(path string, failed []*failedItem) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.WithMessagef(err, "open dead-letter file %s failed", path)
+	}
+	defer f.Close()
+
+	for _, item := range failed {
+		doc, err := c.codec.Encode(item.event)
+		if err != nil {
+			return errors.WithMessagef(err, "codec encode dead-letter event: %s error", item.event.String())
+		}
+
+		data, err := json.Marshal(deadLetterLine{Header: item.event.Header(), Document: doc})
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(append(data, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
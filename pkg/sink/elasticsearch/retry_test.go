@@ -0,0 +1,238 @@
+/*
+Copyright 2021 Loggie Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/loggie-io/loggie/pkg/core/api"
+	"github.com/loggie-io/loggie/pkg/sink/codec"
+	"github.com/loggie-io/loggie/pkg/sink/elasticsearch/backend"
+	es "github.com/olivere/elastic/v7"
+)
+
+// fakeEvent embeds api.Event so it satisfies the interface without
+// implementing every method; only Header and String are exercised here.
+type fakeEvent struct {
+	api.Event
+	header map[string]interface{}
+}
+
+func (f *fakeEvent) Header() map[string]interface{} { return f.header }
+func (f *fakeEvent) String() string                 { return "fake-event" }
+
+// fakeCodec embeds codec.Codec for the same reason as fakeEvent.
+type fakeCodec struct {
+	codec.Codec
+}
+
+func (fakeCodec) Encode(e api.Event) ([]byte, error) {
+	return []byte(`{"message":"hello"}`), nil
+}
+
+// fakeTransport replays one canned response per call, in order, so tests can
+// simulate a transient failure followed by a success without a real server.
+type fakeTransport struct {
+	responses []string
+	calls     int
+}
+
+func (t *fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Path == "/" {
+		// version ping used by es.NewClient health/sniff checks
+		body := `{"version":{"number":"7.10.0"}}`
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(bytes.NewBufferString(body)),
+			Header:     make(http.Header),
+		}, nil
+	}
+
+	idx := t.calls
+	if idx >= len(t.responses) {
+		idx = len(t.responses) - 1
+	}
+	t.calls++
+
+	return &http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(bytes.NewBufferString(t.responses[idx])),
+		Header:     make(http.Header),
+	}, nil
+}
+
+func newTestClientSet(t *testing.T, cfg Config, transport http.RoundTripper) *ClientSet {
+	t.Helper()
+	cli, err := es.NewClient(
+		es.SetURL("http://fake-es:9200"),
+		es.SetSniff(false),
+		es.SetHealthcheck(false),
+		es.SetHttpClient(&http.Client{Transport: transport}),
+	)
+	if err != nil {
+		t.Fatalf("new es client: %v", err)
+	}
+	return &ClientSet{cli: backend.NewElasticBackendWithClient(cli), config: &cfg, codec: fakeCodec{}}
+}
+
+func newTestItem(id string) *bulkItem {
+	return &bulkItem{
+		event:  &fakeEvent{header: map[string]interface{}{}},
+		action: backend.BulkAction{Index: "test", DocumentId: id, Doc: []byte(`{"message":"hello"}`)},
+	}
+}
+
+func TestBackoffNext(t *testing.T) {
+	bo := newBackoff(RetryConfig{
+		InitialInterval:     100 * time.Millisecond,
+		MaxInterval:         1 * time.Second,
+		Multiplier:          2,
+		RandomizationFactor: 0,
+	})
+
+	if got := bo.next(0); got != 100*time.Millisecond {
+		t.Errorf("attempt 0: got %v, want 100ms", got)
+	}
+	if got := bo.next(1); got != 200*time.Millisecond {
+		t.Errorf("attempt 1: got %v, want 200ms", got)
+	}
+	if got := bo.next(10); got != 1*time.Second {
+		t.Errorf("attempt 10: got %v, want capped at 1s", got)
+	}
+}
+
+func TestSplitBulkResult(t *testing.T) {
+	items := []*bulkItem{newTestItem("1"), newTestItem("2"), newTestItem("3"), newTestItem("4")}
+	ret := &backend.BulkResult{
+		Errors: true,
+		Items: []backend.BulkItemResult{
+			{Status: 200},
+			{Status: 429, Error: "too many requests"},
+			{Status: 503, Error: "unavailable"},
+			{Status: 400, Error: "mapper_parsing_exception"},
+		},
+	}
+
+	retryable, failed := splitBulkResult(items, ret)
+
+	if len(retryable) != 2 {
+		t.Fatalf("expected 2 retryable items, got %d", len(retryable))
+	}
+	if len(failed) != 1 {
+		t.Fatalf("expected 1 permanently failed item, got %d", len(failed))
+	}
+	if failed[0].reason != "mapper_parsing_exception" {
+		t.Errorf("unexpected failure reason: %s", failed[0].reason)
+	}
+}
+
+func TestDoBulkWithRetry_SucceedsAfterTransientError(t *testing.T) {
+	transport := &fakeTransport{responses: []string{
+		`{"errors":true,"items":[{"index":{"status":429,"error":{"reason":"too many requests"}}}]}`,
+		`{"errors":false,"items":[{"index":{"status":200}}]}`,
+	}}
+	cs := newTestClientSet(t, Config{Retry: RetryConfig{MaxRetries: 2, InitialInterval: time.Millisecond}}, transport)
+
+	err := cs.doBulkWithRetry(context.Background(), []*bulkItem{newTestItem("1")})
+	if err != nil {
+		t.Fatalf("expected success after retry, got: %v", err)
+	}
+	if transport.calls != 2 {
+		t.Errorf("expected 2 bulk calls, got %d", transport.calls)
+	}
+}
+
+func TestDoBulkWithRetry_AccumulatesPermanentFailuresAcrossAttempts(t *testing.T) {
+	// First attempt: item "1" fails permanently, item "2" is transient.
+	// Second attempt only resubmits item "2", which then succeeds. The
+	// overall call must still dead-letter item "1" instead of dropping it.
+	transport := &fakeTransport{responses: []string{
+		`{"errors":true,"items":[` +
+			`{"index":{"status":400,"error":{"reason":"mapper_parsing_exception"}}},` +
+			`{"index":{"status":429,"error":{"reason":"too many requests"}}}]}`,
+		`{"errors":false,"items":[{"index":{"status":200}}]}`,
+	}}
+	dlFile := filepath.Join(t.TempDir(), "dead-letter.jsonl")
+	cs := newTestClientSet(t, Config{Retry: RetryConfig{
+		MaxRetries: 2, InitialInterval: time.Millisecond,
+		DeadLetter: DeadLetterConfig{File: dlFile},
+	}}, transport)
+
+	err := cs.doBulkWithRetry(context.Background(), []*bulkItem{newTestItem("1"), newTestItem("2")})
+	if err != nil {
+		t.Fatalf("expected overall success once the retryable item lands, got: %v", err)
+	}
+
+	data, err := os.ReadFile(dlFile)
+	if err != nil {
+		t.Fatalf("reading dead-letter file: %v", err)
+	}
+	var line deadLetterLine
+	if err := json.Unmarshal(bytes.TrimSpace(data), &line); err != nil {
+		t.Fatalf("unmarshal dead-letter line: %v", err)
+	}
+	if reason, _ := line.Header[deadLetterReasonHeader].(string); reason != "mapper_parsing_exception" {
+		t.Errorf("expected the permanently-failed item to be dead-lettered, got header %v", line.Header)
+	}
+}
+
+func TestDoBulkWithRetry_DeadLettersPermanentFailure(t *testing.T) {
+	transport := &fakeTransport{responses: []string{
+		`{"errors":true,"items":[{"index":{"status":400,"error":{"reason":"mapper_parsing_exception"}}}]}`,
+	}}
+	dlFile := filepath.Join(t.TempDir(), "dead-letter.jsonl")
+	cs := newTestClientSet(t, Config{Retry: RetryConfig{
+		MaxRetries: 2, InitialInterval: time.Millisecond,
+		DeadLetter: DeadLetterConfig{File: dlFile},
+	}}, transport)
+
+	item := newTestItem("1")
+	if err := cs.doBulkWithRetry(context.Background(), []*bulkItem{item}); err != nil {
+		t.Fatalf("expected dead-letter routing to swallow the error, got: %v", err)
+	}
+
+	data, err := os.ReadFile(dlFile)
+	if err != nil {
+		t.Fatalf("reading dead-letter file: %v", err)
+	}
+	if len(data) == 0 {
+		t.Fatal("expected dead-letter file to contain the failed event")
+	}
+
+	var line deadLetterLine
+	if err := json.Unmarshal(bytes.TrimSpace(data), &line); err != nil {
+		t.Fatalf("unmarshal dead-letter line: %v", err)
+	}
+	if string(line.Document) != `{"message":"hello"}` {
+		t.Errorf("expected dead-letter line to carry the encoded document, got %q", line.Document)
+	}
+
+	if reason, _ := line.Header[deadLetterReasonHeader].(string); reason != "mapper_parsing_exception" {
+		t.Errorf("expected event header to carry the ES error reason, got %q", reason)
+	}
+	if reason, _ := item.event.Header()[deadLetterReasonHeader].(string); reason != "mapper_parsing_exception" {
+		t.Errorf("expected event header to carry the ES error reason, got %q", reason)
+	}
+}